@@ -0,0 +1,143 @@
+package stats
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// minFastStatus and maxFastStatus bound the fixed-size fast path in
+// atomicCounters to the HTTP status codes actually in use (1xx-5xx).
+// Anything outside that range (or a caller passing a bogus status) falls
+// back to the slow, map-based path.
+const (
+	minFastStatus   = 100
+	maxFastStatus   = 599
+	fastStatusCount = maxFastStatus - minFastStatus + 1
+)
+
+// atomicCounters is a per-status-code counter with a lock-free fast path
+// for the common HTTP status range, so EndWithOptions doesn't have to
+// take mu just to increment a counter on every request. Rare/invalid
+// codes fall back to a map guarded by an RWMutex.
+type atomicCounters struct {
+	fast [fastStatusCount]atomic.Int64
+
+	mu   sync.RWMutex
+	slow map[int]*atomic.Int64
+}
+
+func newAtomicCounters() *atomicCounters {
+	return &atomicCounters{slow: map[int]*atomic.Int64{}}
+}
+
+func (c *atomicCounters) add(status int, delta int64) {
+	if status >= minFastStatus && status <= maxFastStatus {
+		c.fast[status-minFastStatus].Add(delta)
+		return
+	}
+
+	c.mu.RLock()
+	counter, ok := c.slow[status]
+	c.mu.RUnlock()
+
+	if !ok {
+		c.mu.Lock()
+		counter, ok = c.slow[status]
+		if !ok {
+			counter = new(atomic.Int64)
+			c.slow[status] = counter
+		}
+		c.mu.Unlock()
+	}
+
+	counter.Add(delta)
+}
+
+// snapshot returns the current counts keyed by status code string,
+// omitting codes that have never been observed.
+func (c *atomicCounters) snapshot() map[string]int {
+	out := map[string]int{}
+
+	for i := range c.fast {
+		if n := c.fast[i].Load(); n != 0 {
+			out[strconv.Itoa(i+minFastStatus)] = int(n)
+		}
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for status, counter := range c.slow {
+		if n := counter.Load(); n != 0 {
+			out[strconv.Itoa(status)] = int(n)
+		}
+	}
+
+	return out
+}
+
+// reset zeroes every counter without removing slow-path entries, so
+// concurrent add calls always find an existing *atomic.Int64 to bump.
+func (c *atomicCounters) reset() {
+	for i := range c.fast {
+		c.fast[i].Store(0)
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, counter := range c.slow {
+		counter.Store(0)
+	}
+}
+
+// methodStatusCounters tracks per-status counts broken down by HTTP
+// method, giving StatusCodeCountByMethod the same lock-free-on-the-hot-path
+// shape as atomicCounters: an atomicCounters per method, reached through
+// an RWMutex-guarded map. Methods are far lower cardinality than requests,
+// so the map lookup is cheap and the Lock branch is rare (taken only the
+// first time a given method is observed), never on every request.
+type methodStatusCounters struct {
+	mu      sync.RWMutex
+	methods map[string]*atomicCounters
+}
+
+func newMethodStatusCounters() *methodStatusCounters {
+	return &methodStatusCounters{methods: map[string]*atomicCounters{}}
+}
+
+func (c *methodStatusCounters) add(method string, status int, delta int64) {
+	c.mu.RLock()
+	counters, ok := c.methods[method]
+	c.mu.RUnlock()
+
+	if !ok {
+		c.mu.Lock()
+		counters, ok = c.methods[method]
+		if !ok {
+			counters = newAtomicCounters()
+			c.methods[method] = counters
+		}
+		c.mu.Unlock()
+	}
+
+	counters.add(status, delta)
+}
+
+// snapshot returns the current counts keyed by method, then status code
+// string, omitting methods that have never been observed.
+func (c *methodStatusCounters) snapshot() map[string]map[string]int {
+	c.mu.RLock()
+	methods := make([]string, 0, len(c.methods))
+	counters := make([]*atomicCounters, 0, len(c.methods))
+	for method, ac := range c.methods {
+		methods = append(methods, method)
+		counters = append(counters, ac)
+	}
+	c.mu.RUnlock()
+
+	out := make(map[string]map[string]int, len(methods))
+	for i, method := range methods {
+		out[method] = counters[i].snapshot()
+	}
+	return out
+}
@@ -0,0 +1,239 @@
+package stats
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// Quantiler is a bounded-memory streaming quantile estimator. Stats feeds
+// every observed response time into one via Observe, then reads back
+// percentiles for Data. The default implementation is a t-digest-style
+// centroid sketch; swap in your own (e.g. an HDR-histogram-backed fixed
+// bucket estimator) with SetLatencyQuantiler when the defaults don't fit.
+type Quantiler interface {
+	// Observe records a single sample.
+	Observe(value float64)
+
+	// Quantile returns the estimated value at rank q, 0 <= q <= 1.
+	Quantile(q float64) float64
+
+	// Min and Max return the smallest/largest observed sample, or 0 if
+	// Observe has never been called.
+	Min() float64
+	Max() float64
+
+	// Reset clears all observations.
+	Reset()
+}
+
+// centroid is a single weighted mean tracked by digest.
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+// digest is the default Quantiler: a streaming centroid sketch in the
+// t-digest / Cormode-Korolova-Muthukrishnan family. Centroids near the
+// median are allowed to absorb more samples than centroids near the
+// tails (bounded by N*q*(1-q)/compression), which keeps memory bounded
+// while still giving good accuracy at the percentiles dashboards care
+// about most (p99, p50, ...).
+type digest struct {
+	mu            sync.Mutex
+	compression   float64
+	centroids     []centroid
+	count         float64
+	min, max      float64
+	sinceCompress int
+}
+
+// compressEvery controls how often digest re-sorts and re-merges its
+// centroids; insert alone only ever merges into the nearest centroid, so
+// periodic compression is what actually keeps centroid count bounded.
+const compressEvery = 256
+
+// NewDigest constructs the default Quantiler. compression controls the
+// accuracy/memory trade-off (the k in N*q*(1-q)/k) — higher values keep
+// more, smaller centroids. A compression of 100 is a reasonable default
+// for response-time percentiles.
+func NewDigest(compression float64) Quantiler {
+	if compression <= 0 {
+		compression = 100
+	}
+
+	return &digest{compression: compression}
+}
+
+func (d *digest) Observe(value float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.count == 0 {
+		d.min, d.max = value, value
+	} else if value < d.min {
+		d.min = value
+	} else if value > d.max {
+		d.max = value
+	}
+
+	d.count++
+	d.insert(value, 1)
+
+	d.sinceCompress++
+	if d.sinceCompress >= compressEvery {
+		d.compress()
+		d.sinceCompress = 0
+	}
+}
+
+// insert merges value into the nearest centroid if doing so wouldn't
+// push that centroid's weight past its rank-based size bound, otherwise
+// it inserts a new centroid, keeping centroids sorted by mean.
+func (d *digest) insert(value, weight float64) {
+	n := len(d.centroids)
+	if n == 0 {
+		d.centroids = append(d.centroids, centroid{mean: value, weight: weight})
+		return
+	}
+
+	idx := sort.Search(n, func(i int) bool { return d.centroids[i].mean >= value })
+
+	best := -1
+	bestDist := math.MaxFloat64
+	for _, i := range [...]int{idx - 1, idx} {
+		if i < 0 || i >= n {
+			continue
+		}
+		if dist := math.Abs(d.centroids[i].mean - value); dist < bestDist {
+			bestDist = dist
+			best = i
+		}
+	}
+
+	cumulative := 0.0
+	for i := 0; i < best; i++ {
+		cumulative += d.centroids[i].weight
+	}
+
+	// q is the rank of the mass strictly before best, not best's own
+	// midpoint: with a single centroid that absorbs every sample,
+	// cumulative is always 0 regardless of how much weight the centroid
+	// has accumulated, so the size bound actually shrinks toward the
+	// tails instead of tracking the centroid's own (unbounded) weight.
+	q := cumulative / d.count
+	limit := d.count * q * (1 - q) / d.compression
+	if limit < 1 {
+		limit = 1
+	}
+
+	if d.centroids[best].weight+weight <= limit {
+		c := d.centroids[best]
+		newWeight := c.weight + weight
+		c.mean += (value - c.mean) * (weight / newWeight)
+		c.weight = newWeight
+		d.centroids[best] = c
+		return
+	}
+
+	d.centroids = append(d.centroids, centroid{})
+	copy(d.centroids[idx+1:], d.centroids[idx:])
+	d.centroids[idx] = centroid{mean: value, weight: weight}
+}
+
+// compress re-sorts centroids by mean and merges adjacent ones that are
+// still within their size bound, undoing any drift insert's merges
+// introduce and bounding how many centroids digest keeps overall.
+func (d *digest) compress() {
+	sort.Slice(d.centroids, func(i, j int) bool { return d.centroids[i].mean < d.centroids[j].mean })
+
+	merged := make([]centroid, 0, len(d.centroids))
+	cumulative := 0.0
+
+	for _, c := range d.centroids {
+		if len(merged) == 0 {
+			merged = append(merged, c)
+			cumulative += c.weight
+			continue
+		}
+
+		last := merged[len(merged)-1]
+		// Same rank-before-the-candidate convention as insert, applied
+		// to the already-merged `last` centroid.
+		q := (cumulative - last.weight) / d.count
+		limit := d.count * q * (1 - q) / d.compression
+		if limit < 1 {
+			limit = 1
+		}
+
+		if last.weight+c.weight <= limit {
+			newWeight := last.weight + c.weight
+			last.mean += (c.mean - last.mean) * (c.weight / newWeight)
+			last.weight = newWeight
+			merged[len(merged)-1] = last
+		} else {
+			merged = append(merged, c)
+		}
+		cumulative += c.weight
+	}
+
+	d.centroids = merged
+}
+
+// Quantile walks the centroids accumulating weight until the target
+// rank is crossed, then linearly interpolates between the previous and
+// current centroid means.
+func (d *digest) Quantile(q float64) float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.centroids) == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return d.min
+	}
+	if q >= 1 {
+		return d.max
+	}
+
+	target := q * d.count
+	cumulative := 0.0
+	prevMean := d.min
+
+	for _, c := range d.centroids {
+		next := cumulative + c.weight
+		if target <= next {
+			if next == cumulative {
+				return c.mean
+			}
+			frac := (target - cumulative) / (next - cumulative)
+			return prevMean + frac*(c.mean-prevMean)
+		}
+		cumulative = next
+		prevMean = c.mean
+	}
+
+	return d.max
+}
+
+func (d *digest) Min() float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.min
+}
+
+func (d *digest) Max() float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.max
+}
+
+func (d *digest) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.centroids = nil
+	d.count = 0
+	d.min, d.max = 0, 0
+	d.sinceCompress = 0
+}
@@ -0,0 +1,75 @@
+package stats
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// approxEqual reports whether got is within tol of want, scaled to the
+// size of a 0-1000 test distribution.
+func approxEqual(t *testing.T, label string, got, want, tol float64) {
+	t.Helper()
+	if math.Abs(got-want) > tol {
+		t.Errorf("%s = %v, want within %v of %v", label, got, tol, want)
+	}
+}
+
+func TestDigestQuantileSequential(t *testing.T) {
+	d := NewDigest(100)
+	for i := 1; i <= 1000; i++ {
+		d.Observe(float64(i))
+	}
+
+	approxEqual(t, "p50", d.Quantile(0.50), 500, 50)
+	approxEqual(t, "p90", d.Quantile(0.90), 900, 50)
+	approxEqual(t, "p99", d.Quantile(0.99), 990, 30)
+
+	if min := d.Min(); min != 1 {
+		t.Errorf("Min() = %v, want 1", min)
+	}
+	if max := d.Max(); max != 1000 {
+		t.Errorf("Max() = %v, want 1000", max)
+	}
+}
+
+// TestDigestQuantileUniformRandom feeds a large uniform distribution
+// through digest (well past compressEvery) and checks both the
+// percentiles and that the centroid count stays bounded independent of
+// the sample count, the property chunk0-3's original size-bound bug broke.
+func TestDigestQuantileUniformRandom(t *testing.T) {
+	d := NewDigest(100).(*digest)
+
+	r := rand.New(rand.NewSource(42))
+	const n = 200000
+	for i := 0; i < n; i++ {
+		d.Observe(r.Float64() * 1000)
+	}
+
+	approxEqual(t, "p50", d.Quantile(0.50), 500, 25)
+	approxEqual(t, "p90", d.Quantile(0.90), 900, 25)
+	approxEqual(t, "p99", d.Quantile(0.99), 990, 25)
+
+	if got := len(d.centroids); got < 2 || got > n/10 {
+		t.Errorf("centroid count = %d, want a small bounded number (not collapsed to 1, not tracking n=%d)", got, n)
+	}
+}
+
+func TestDigestReset(t *testing.T) {
+	d := NewDigest(100)
+	for i := 1; i <= 100; i++ {
+		d.Observe(float64(i))
+	}
+
+	d.Reset()
+
+	if got := d.Quantile(0.5); got != 0 {
+		t.Errorf("Quantile(0.5) after Reset() = %v, want 0", got)
+	}
+	if got := d.Min(); got != 0 {
+		t.Errorf("Min() after Reset() = %v, want 0", got)
+	}
+	if got := d.Max(); got != 0 {
+		t.Errorf("Max() after Reset() = %v, want 0", got)
+	}
+}
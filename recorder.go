@@ -0,0 +1,63 @@
+package stats
+
+import "net/http"
+
+// ResponseWriter wraps http.ResponseWriter so that the status code and the
+// number of bytes written to the response body can be inspected once the
+// handler has finished.
+type ResponseWriter interface {
+	http.ResponseWriter
+
+	// Status returns the HTTP status code of the response, or the
+	// default status passed to NewRecorderResponseWriter if WriteHeader
+	// was never called.
+	Status() int
+
+	// Size returns the number of bytes written to the response body.
+	Size() int
+}
+
+// recorderResponseWriter is the default ResponseWriter implementation used
+// by Begin when the caller doesn't already have a recorder of its own.
+type recorderResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	size        int
+	wroteHeader bool
+}
+
+// NewRecorderResponseWriter wraps w so its status code and response size
+// can be recorded. defaultStatus is reported if the handler never calls
+// WriteHeader explicitly (mirroring the implicit 200 http.ResponseWriter
+// itself assumes).
+func NewRecorderResponseWriter(w http.ResponseWriter, defaultStatus int) ResponseWriter {
+	return &recorderResponseWriter{
+		ResponseWriter: w,
+		status:         defaultStatus,
+	}
+}
+
+func (w *recorderResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recorderResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(w.status)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+func (w *recorderResponseWriter) Status() int {
+	return w.status
+}
+
+func (w *recorderResponseWriter) Size() int {
+	return w.size
+}
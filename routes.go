@@ -0,0 +1,212 @@
+package stats
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// windowBuckets is the number of one-second buckets kept per route, long
+// enough to derive 1m/5m/15m windowed rates (à la Unix load averages) on
+// read instead of losing that history the way the single-second
+// ResetResponseCounts sweep does.
+const windowBuckets = 15 * 60
+
+// errorStatusThreshold is the status code at and above which a request
+// counts as an error in RouteStats.ErrorCount.
+const errorStatusThreshold = 400
+
+// routeSecond is one second's worth of observations for a single
+// (route, method) pair.
+type routeSecond struct {
+	count int64
+}
+
+// routeWindow tracks lifetime counters, latency percentiles and a ring
+// of windowBuckets one-second buckets for a single (route, method) pair.
+type routeWindow struct {
+	mu           sync.Mutex
+	buckets      [windowBuckets]routeSecond
+	cursor       int
+	count        int64
+	errorCount   int64
+	totalLatency time.Duration
+	latency      Quantiler
+}
+
+func newRouteWindow() *routeWindow {
+	return &routeWindow{latency: NewDigest(50)}
+}
+
+func (w *routeWindow) observe(isError bool, responseTime time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buckets[w.cursor].count++
+
+	w.count++
+	if isError {
+		w.errorCount++
+	}
+	w.totalLatency += responseTime
+	w.latency.Observe(responseTime.Seconds())
+}
+
+// tick advances the ring by one second, clearing the bucket it lands on
+// so rate() only ever sums whole seconds of the trailing window.
+func (w *routeWindow) tick() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.cursor = (w.cursor + 1) % windowBuckets
+	w.buckets[w.cursor] = routeSecond{}
+}
+
+// rate returns the average requests/sec over the trailing `seconds`
+// one-second buckets.
+func (w *routeWindow) rate(seconds int) float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var count int64
+	for i := 0; i < seconds; i++ {
+		idx := ((w.cursor-i)%windowBuckets + windowBuckets) % windowBuckets
+		count += w.buckets[idx].count
+	}
+
+	return float64(count) / float64(seconds)
+}
+
+func (w *routeWindow) snapshot() RouteStats {
+	w.mu.Lock()
+	count := w.count
+	errorCount := w.errorCount
+	totalLatency := w.totalLatency
+	latency := w.latency
+	w.mu.Unlock()
+
+	average := 0.0
+	if count > 0 {
+		average = totalLatency.Seconds() / float64(count)
+	}
+
+	return RouteStats{
+		Count:                  count,
+		ErrorCount:             errorCount,
+		AverageResponseTimeSec: average,
+		P50ResponseTimeSec:     latency.Quantile(0.50),
+		P90ResponseTimeSec:     latency.Quantile(0.90),
+		P99ResponseTimeSec:     latency.Quantile(0.99),
+		Rate1m:                 w.rate(60),
+		Rate5m:                 w.rate(5 * 60),
+		Rate15m:                w.rate(15 * 60),
+	}
+}
+
+// routeTracker keys routeWindows by "METHOD route".
+type routeTracker struct {
+	mu     sync.Mutex
+	routes map[string]*routeWindow
+}
+
+func newRouteTracker() *routeTracker {
+	return &routeTracker{routes: map[string]*routeWindow{}}
+}
+
+func routeTrackerKey(method, route string) string {
+	return method + " " + route
+}
+
+func (t *routeTracker) observe(method, route string, status int, responseTime time.Duration) {
+	key := routeTrackerKey(method, route)
+
+	t.mu.Lock()
+	w := t.routes[key]
+	if w == nil {
+		w = newRouteWindow()
+		t.routes[key] = w
+	}
+	t.mu.Unlock()
+
+	w.observe(status >= errorStatusThreshold, responseTime)
+}
+
+func (t *routeTracker) tick() {
+	t.mu.Lock()
+	windows := make([]*routeWindow, 0, len(t.routes))
+	for _, w := range t.routes {
+		windows = append(windows, w)
+	}
+	t.mu.Unlock()
+
+	for _, w := range windows {
+		w.tick()
+	}
+}
+
+func (t *routeTracker) snapshot() map[string]RouteStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make(map[string]RouteStats, len(t.routes))
+	for key, w := range t.routes {
+		out[key] = w.snapshot()
+	}
+
+	return out
+}
+
+// RouteStats is the per (route, method) breakdown exposed by
+// DataDetailed: lifetime counts and latency percentiles, plus windowed
+// request rates over the trailing 1m/5m/15m.
+type RouteStats struct {
+	Count                  int64   `json:"count"`
+	ErrorCount             int64   `json:"error_count"`
+	AverageResponseTimeSec float64 `json:"average_response_time_sec"`
+	P50ResponseTimeSec     float64 `json:"p50_response_time_sec"`
+	P90ResponseTimeSec     float64 `json:"p90_response_time_sec"`
+	P99ResponseTimeSec     float64 `json:"p99_response_time_sec"`
+	Rate1m                 float64 `json:"rate_1m"`
+	Rate5m                 float64 `json:"rate_5m"`
+	Rate15m                float64 `json:"rate_15m"`
+}
+
+// DetailedData extends Data with the per-route/method breakdown.
+type DetailedData struct {
+	*Data
+	Routes map[string]RouteStats `json:"routes"`
+}
+
+// DataDetailed returns Data plus a Routes breakdown keyed by "METHOD
+// route" (the route being whatever pattern was passed to HandlerFor/
+// HandlerRoute/ObserveRoute).
+func (mw *Stats) DataDetailed() *DetailedData {
+	return &DetailedData{
+		Data:   mw.Data(),
+		Routes: mw.routes.snapshot(),
+	}
+}
+
+// HandlerFor wraps h so requests are recorded under the explicit route
+// pattern rather than reconstructed from r.URL.Path, which wouldn't know
+// about path parameters (e.g. "/users/{id}" vs "/users/42").
+func (mw *Stats) HandlerFor(pattern string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start, recorder := mw.Begin(w)
+
+		h.ServeHTTP(recorder, r)
+
+		status := recorder.Status()
+		duration := time.Since(start)
+		size := recorder.Size()
+
+		mw.ObserveRoute(r.Method, pattern, status, duration)
+		mw.EndWithOptions(start, Options{
+			StatusCode: &status,
+			Size:       size,
+			Method:     r.Method,
+			Route:      pattern,
+			Duration:   &duration,
+		})
+	})
+}
@@ -0,0 +1,81 @@
+package stats
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestHandlerForDataDetailed drives requests through HandlerFor against
+// two different route patterns and checks DataDetailed reports a
+// correctly keyed, correctly counted breakdown for each.
+func TestHandlerForDataDetailed(t *testing.T) {
+	mw := New(WithoutResetter())
+	defer mw.Close()
+
+	ok := mw.HandlerFor("/users/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	notFound := mw.HandlerFor("/missing", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+		ok.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	notFound.ServeHTTP(httptest.NewRecorder(), req)
+
+	data := mw.DataDetailed()
+
+	usersRoute, ok2 := data.Routes[routeTrackerKey(http.MethodGet, "/users/{id}")]
+	if !ok2 {
+		t.Fatalf("Routes missing key for /users/{id}, got %v", data.Routes)
+	}
+	if usersRoute.Count != 3 {
+		t.Errorf("/users/{id} Count = %d, want 3", usersRoute.Count)
+	}
+	if usersRoute.ErrorCount != 0 {
+		t.Errorf("/users/{id} ErrorCount = %d, want 0", usersRoute.ErrorCount)
+	}
+
+	missingRoute, ok2 := data.Routes[routeTrackerKey(http.MethodGet, "/missing")]
+	if !ok2 {
+		t.Fatalf("Routes missing key for /missing, got %v", data.Routes)
+	}
+	if missingRoute.Count != 1 {
+		t.Errorf("/missing Count = %d, want 1", missingRoute.Count)
+	}
+	if missingRoute.ErrorCount != 1 {
+		t.Errorf("/missing ErrorCount = %d, want 1 (404 >= errorStatusThreshold)", missingRoute.ErrorCount)
+	}
+
+	if data.Data.TotalCount != 4 {
+		t.Errorf("Data.TotalCount = %d, want 4", data.Data.TotalCount)
+	}
+}
+
+// TestRouteWindowRateFixedOneSecondTick drives tick() by hand, standing in
+// for the fixed 1-second ticker New runs independent of WithResetInterval,
+// and checks that rate(60) still counts requests observed in the buckets
+// the ring has advanced past.
+func TestRouteWindowRateFixedOneSecondTick(t *testing.T) {
+	w := newRouteWindow()
+
+	w.observe(false, 0)
+	w.observe(false, 0)
+	w.observe(false, 0)
+
+	// Simulate three real seconds elapsing: three requests in the first,
+	// one in each of the next two.
+	w.tick()
+	w.observe(false, 0)
+	w.tick()
+	w.observe(false, 0)
+	w.tick()
+
+	if got := w.rate(60); got == 0 {
+		t.Fatalf("rate(60) = 0, want > 0 after observed requests within the last 60 buckets")
+	}
+}
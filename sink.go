@@ -0,0 +1,131 @@
+package stats
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sample is a single observed request, handed to every configured Sink.
+type Sample struct {
+	Method   string
+	Route    string
+	Code     int
+	Duration time.Duration
+	Size     int
+	Time     time.Time
+}
+
+// Sink receives samples fanned out from EndWithOptions, in addition to
+// the in-memory aggregation Stats already performs. Built-in
+// implementations: NewStatsDSink, NewInfluxUDPSink, NewInfluxHTTPSink, and
+// statsotel.NewOTelSink in the statsotel subpackage.
+type Sink interface {
+	// Record is called once per observed request, from a background
+	// goroutine rather than the request path (see sinkDispatcher).
+	Record(sample Sample)
+
+	// Flush gives the sink a chance to push any buffered samples before
+	// ctx is done.
+	Flush(ctx context.Context) error
+}
+
+const (
+	defaultSinkBufferSize    = 1024
+	defaultSinkFlushInterval = 10 * time.Second
+)
+
+// sinkDispatcher feeds one Sink from a bounded channel, so a slow or
+// unreachable sink can't add latency to the request path: enqueue
+// returns immediately, dropping the sample (and counting it) if the
+// channel is already full.
+type sinkDispatcher struct {
+	sink    Sink
+	samples chan Sample
+	flush   time.Duration
+	dropped atomic.Int64
+
+	closed    chan struct{}
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func newSinkDispatcher(sink Sink, bufferSize int, flushInterval time.Duration) *sinkDispatcher {
+	if bufferSize <= 0 {
+		bufferSize = defaultSinkBufferSize
+	}
+	if flushInterval <= 0 {
+		flushInterval = defaultSinkFlushInterval
+	}
+
+	d := &sinkDispatcher{
+		sink:    sink,
+		samples: make(chan Sample, bufferSize),
+		flush:   flushInterval,
+		closed:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go d.run()
+
+	return d
+}
+
+func (d *sinkDispatcher) enqueue(sample Sample) {
+	select {
+	case d.samples <- sample:
+	default:
+		d.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of samples dropped because the bounded
+// channel was full, for monitoring sink health.
+func (d *sinkDispatcher) Dropped() int64 {
+	return d.dropped.Load()
+}
+
+func (d *sinkDispatcher) run() {
+	defer close(d.done)
+
+	ticker := time.NewTicker(d.flush)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case sample := <-d.samples:
+			d.sink.Record(sample)
+		case <-ticker.C:
+			d.flushSink()
+		case <-d.closed:
+			d.drain()
+			d.flushSink()
+			return
+		}
+	}
+}
+
+func (d *sinkDispatcher) drain() {
+	for {
+		select {
+		case sample := <-d.samples:
+			d.sink.Record(sample)
+		default:
+			return
+		}
+	}
+}
+
+func (d *sinkDispatcher) flushSink() {
+	ctx, cancel := context.WithTimeout(context.Background(), d.flush)
+	defer cancel()
+	d.sink.Flush(ctx)
+}
+
+// close stops the dispatcher goroutine after draining and flushing
+// whatever samples are still buffered.
+func (d *sinkDispatcher) close() {
+	d.closeOnce.Do(func() { close(d.closed) })
+	<-d.done
+}
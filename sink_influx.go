@@ -0,0 +1,119 @@
+package stats
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// InfluxLineSink batches samples as InfluxDB line protocol points
+// (measurement defaulting to "http_request", tags host/method/route/code,
+// fields duration_ns/size_bytes) and writes them over UDP or HTTP
+// /write on Flush.
+type InfluxLineSink struct {
+	measurement string
+	host        string
+
+	udpConn net.Conn // set when writing over UDP
+	httpURL string   // set when writing over HTTP /write
+	client  *http.Client
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// NewInfluxUDPSink writes line-protocol points to addr (host:port) over
+// UDP, matching InfluxDB's UDP input plugin.
+func NewInfluxUDPSink(addr, measurement string) (*InfluxLineSink, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return newInfluxLineSink(measurement, conn, ""), nil
+}
+
+// NewInfluxHTTPSink writes line-protocol points to writeURL (an
+// InfluxDB /write?db=... endpoint) over HTTP on Flush.
+func NewInfluxHTTPSink(writeURL, measurement string) *InfluxLineSink {
+	return newInfluxLineSink(measurement, nil, writeURL)
+}
+
+func newInfluxLineSink(measurement string, udpConn net.Conn, httpURL string) *InfluxLineSink {
+	if measurement == "" {
+		measurement = "http_request"
+	}
+
+	host, _ := os.Hostname()
+
+	return &InfluxLineSink{
+		measurement: measurement,
+		host:        host,
+		udpConn:     udpConn,
+		httpURL:     httpURL,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *InfluxLineSink) Record(sample Sample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fmt.Fprintf(&s.buf, "%s,host=%s,method=%s,route=%s,code=%d duration_ns=%d,size_bytes=%d %d\n",
+		s.measurement,
+		escapeTag(s.host),
+		escapeTag(sample.Method),
+		escapeTag(sample.Route),
+		sample.Code,
+		sample.Duration.Nanoseconds(),
+		sample.Size,
+		sample.Time.UnixNano(),
+	)
+}
+
+// Flush writes any buffered points over UDP, or POSTs them to the
+// configured InfluxDB /write endpoint.
+func (s *InfluxLineSink) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	data := s.buf.String()
+	s.buf.Reset()
+	s.mu.Unlock()
+
+	if data == "" {
+		return nil
+	}
+
+	if s.udpConn != nil {
+		_, err := s.udpConn.Write([]byte(data))
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.httpURL, strings.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("stats: influx write failed with status %s", resp.Status)
+	}
+
+	return nil
+}
+
+// escapeTag escapes the characters InfluxDB line protocol treats
+// specially in tag keys/values: commas, spaces and equals signs.
+func escapeTag(v string) string {
+	return strings.NewReplacer(",", "\\,", " ", "\\ ", "=", "\\=").Replace(v)
+}
@@ -0,0 +1,90 @@
+package stats
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInfluxUDPSinkWritesLineProtocol(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket: %v", err)
+	}
+	defer conn.Close()
+
+	sink, err := NewInfluxUDPSink(conn.LocalAddr().String(), "")
+	if err != nil {
+		t.Fatalf("NewInfluxUDPSink: %v", err)
+	}
+
+	sink.Record(Sample{Method: "GET", Route: "/a,b c", Code: 200, Duration: 5 * time.Millisecond, Size: 128})
+
+	if err := sink.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 4096)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("ReadFrom: %v", err)
+	}
+
+	got := string(buf[:n])
+	if !strings.HasPrefix(got, "http_request,") {
+		t.Errorf("line %q missing default measurement", got)
+	}
+	if !strings.Contains(got, `route=/a\,b\ c`) {
+		t.Errorf("line %q missing escaped route tag", got)
+	}
+	if !strings.Contains(got, "duration_ns=5000000") {
+		t.Errorf("line %q missing duration_ns field", got)
+	}
+	if !strings.Contains(got, "size_bytes=128") {
+		t.Errorf("line %q missing size_bytes field", got)
+	}
+}
+
+func TestInfluxHTTPSinkPostsToWriteEndpoint(t *testing.T) {
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	sink := NewInfluxHTTPSink(srv.URL+"/write", "requests")
+	sink.Record(Sample{Method: "POST", Route: "/orders", Code: 201, Duration: time.Millisecond, Size: 64})
+
+	if err := sink.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	if !strings.HasPrefix(body, "requests,") {
+		t.Errorf("posted body %q missing configured measurement", body)
+	}
+	if !strings.Contains(body, "method=POST") {
+		t.Errorf("posted body %q missing method tag", body)
+	}
+}
+
+func TestInfluxHTTPSinkErrorsOnNon2xx(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	sink := NewInfluxHTTPSink(srv.URL+"/write", "requests")
+	sink.Record(Sample{Method: "GET", Route: "/x", Code: 500})
+
+	if err := sink.Flush(context.Background()); err == nil {
+		t.Fatal("Flush() error = nil, want error on 500 response")
+	}
+}
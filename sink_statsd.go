@@ -0,0 +1,107 @@
+package stats
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// StatsDSink writes samples to a StatsD (or DogStatsD, using its tag
+// extension) daemon over UDP: a timer for duration, a counter per
+// request and a histogram for response size. Writes are buffered and
+// flushed in one or more UDP datagrams by Flush.
+type StatsDSink struct {
+	conn      net.Conn
+	prefix    string
+	dogstatsd bool
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// NewStatsDSink dials addr (host:port) over UDP and returns a Sink that
+// writes StatsD metrics to it. prefix, if non-empty, is prepended to
+// every metric name followed by a dot. Set dogstatsd to true to tag
+// metrics with method/route/code via the `|#tag:value` extension instead
+// of folding them into the metric name.
+func NewStatsDSink(addr, prefix string, dogstatsd bool) (*StatsDSink, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StatsDSink{conn: conn, prefix: prefix, dogstatsd: dogstatsd}, nil
+}
+
+func (s *StatsDSink) metric(name string) string {
+	if s.prefix == "" {
+		return name
+	}
+	return s.prefix + "." + name
+}
+
+func (s *StatsDSink) Record(sample Sample) {
+	var tags string
+	if s.dogstatsd {
+		tags = fmt.Sprintf("|#method:%s,route:%s,code:%d", sample.Method, sample.Route, sample.Code)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fmt.Fprintf(&s.buf, "%s:%d|ms%s\n", s.metric("http.request.duration_ms"), sample.Duration.Milliseconds(), tags)
+	fmt.Fprintf(&s.buf, "%s:1|c%s\n", s.metric("http.requests"), tags)
+	fmt.Fprintf(&s.buf, "%s:%d|h%s\n", s.metric("http.response.size_bytes"), sample.Size, tags)
+}
+
+// Flush writes any buffered metrics to the StatsD daemon, splitting the
+// batch on line boundaries if it would otherwise exceed a conservative
+// UDP datagram size.
+func (s *StatsDSink) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	data := s.buf.String()
+	s.buf.Reset()
+	s.mu.Unlock()
+
+	if data == "" {
+		return nil
+	}
+
+	const maxDatagram = 1400
+
+	for _, chunk := range chunkLines(data, maxDatagram) {
+		if _, err := s.conn.Write([]byte(chunk)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// chunkLines groups newline-terminated lines from data into chunks no
+// longer than maxLen, never splitting a single line across chunks.
+func chunkLines(data string, maxLen int) []string {
+	lines := strings.SplitAfter(data, "\n")
+
+	var chunks []string
+	var current strings.Builder
+
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		if current.Len()+len(line) > maxLen && current.Len() > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+		current.WriteString(line)
+	}
+	if current.Len() > 0 {
+		chunks = append(chunks, current.String())
+	}
+
+	return chunks
+}
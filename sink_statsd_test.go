@@ -0,0 +1,62 @@
+package stats
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatsDSinkWritesMetrics(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket: %v", err)
+	}
+	defer conn.Close()
+
+	sink, err := NewStatsDSink(conn.LocalAddr().String(), "myapp", true)
+	if err != nil {
+		t.Fatalf("NewStatsDSink: %v", err)
+	}
+
+	sink.Record(Sample{Method: "GET", Route: "/users", Code: 200, Duration: 25 * time.Millisecond, Size: 512})
+
+	if err := sink.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 4096)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("ReadFrom: %v", err)
+	}
+
+	got := string(buf[:n])
+	for _, want := range []string{
+		"myapp.http.request.duration_ms:25|ms|#method:GET,route:/users,code:200",
+		"myapp.http.requests:1|c|#method:GET,route:/users,code:200",
+		"myapp.http.response.size_bytes:512|h|#method:GET,route:/users,code:200",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("datagram %q missing line %q", got, want)
+		}
+	}
+}
+
+func TestChunkLinesNeverSplitsALine(t *testing.T) {
+	data := "aaaa\nbb\ncccccccc\n"
+	chunks := chunkLines(data, 6)
+
+	var rebuilt strings.Builder
+	for _, c := range chunks {
+		if len(c) > 6 && !strings.HasSuffix(strings.TrimSuffix(c, "\n"), "cccccccc") {
+			t.Errorf("chunk %q exceeds maxLen without being a single long line", c)
+		}
+		rebuilt.WriteString(c)
+	}
+	if rebuilt.String() != data {
+		t.Errorf("chunks = %q, want reassembled %q", rebuilt.String(), data)
+	}
+}
@@ -0,0 +1,96 @@
+package stats
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSink records every Sample it receives, and optionally blocks inside
+// Record until release is closed, so tests can exercise sinkDispatcher's
+// bounded-channel drop path. started, if non-nil, is closed the first
+// time Record is entered, letting a test wait for the dispatcher to have
+// actually picked up a sample instead of guessing with a sleep.
+type fakeSink struct {
+	mu      sync.Mutex
+	samples []Sample
+	flushes int
+	release chan struct{}
+	started chan struct{}
+}
+
+func (s *fakeSink) Record(sample Sample) {
+	if s.started != nil {
+		select {
+		case <-s.started:
+		default:
+			close(s.started)
+		}
+	}
+	if s.release != nil {
+		<-s.release
+	}
+	s.mu.Lock()
+	s.samples = append(s.samples, sample)
+	s.mu.Unlock()
+}
+
+func (s *fakeSink) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	s.flushes++
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *fakeSink) recorded() []Sample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Sample, len(s.samples))
+	copy(out, s.samples)
+	return out
+}
+
+func TestSinkDispatcherRecordsAndFlushesOnClose(t *testing.T) {
+	sink := &fakeSink{}
+	d := newSinkDispatcher(sink, 0, time.Hour)
+
+	d.enqueue(Sample{Method: "GET", Route: "/a", Code: 200})
+	d.enqueue(Sample{Method: "GET", Route: "/b", Code: 404})
+
+	d.close()
+
+	got := sink.recorded()
+	if len(got) != 2 {
+		t.Fatalf("recorded %d samples, want 2: %+v", len(got), got)
+	}
+	if got[0].Route != "/a" || got[1].Route != "/b" {
+		t.Errorf("samples out of order or wrong: %+v", got)
+	}
+	if sink.flushes == 0 {
+		t.Errorf("Flush was never called; close should flush before returning")
+	}
+}
+
+func TestSinkDispatcherDropsWhenBufferFull(t *testing.T) {
+	sink := &fakeSink{release: make(chan struct{}), started: make(chan struct{})}
+	d := newSinkDispatcher(sink, 1, time.Hour)
+	defer func() {
+		close(sink.release)
+		d.close()
+	}()
+
+	// The first sample is picked up by the dispatcher goroutine and blocks
+	// in Record until release is closed, so every subsequent enqueue has
+	// to contend for the single buffered slot. Wait for Record to actually
+	// be entered rather than guessing with a sleep.
+	d.enqueue(Sample{Route: "/blocked"})
+	<-sink.started
+
+	d.enqueue(Sample{Route: "/buffered"})
+	d.enqueue(Sample{Route: "/dropped"})
+
+	if dropped := d.Dropped(); dropped != 1 {
+		t.Errorf("Dropped() = %d, want 1", dropped)
+	}
+}
@@ -1,10 +1,10 @@
 package stats
 
 import (
-	"fmt"
 	"net/http"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -16,45 +16,212 @@ type Stats struct {
 	Pid                 int
 	ResponseCounts      map[string]int
 	TotalResponseCounts map[string]int
-	TotalResponseTime   time.Time
+
+	// TotalResponseSize is refreshed from totalResponseSize once per
+	// resetInterval, same as ResponseCounts/TotalResponseCounts. Data()
+	// does not depend on this field; it reads totalResponseSize directly.
+	TotalResponseSize int64
+
+	// intervalCounts/totalCounts back ResponseCounts/TotalResponseCounts
+	// with a lock-free fast path, so EndWithOptions doesn't have to take
+	// mu on every request just to bump a counter. ResponseCounts and
+	// TotalResponseCounts are refreshed from them once per reset
+	// interval (see ResetResponseCounts).
+	intervalCounts *atomicCounters
+	totalCounts    *atomicCounters
+
+	// totalResponseTimeNanos replaces the old time.Time-arithmetic
+	// accumulator with a plain atomic counter of nanoseconds, for the
+	// same reason: no lock needed to accumulate it.
+	totalResponseTimeNanos atomic.Int64
+
+	// totalResponseSize and methodStatusCounts hold the two pieces of
+	// per-request bookkeeping that used to go through mu on every call to
+	// EndWithOptions. Neither needs a lock on the hot path any more than
+	// intervalCounts/totalCounts do.
+	totalResponseSize  atomic.Int64
+	methodStatusCounts *methodStatusCounters
+
+	// resetInterval/noResetter are set by WithResetInterval/
+	// WithoutResetter.
+	resetInterval time.Duration
+	noResetter    bool
+
+	// latency estimates response-time percentiles from every sample
+	// observed in EndWithOptions. Swap it out with SetLatencyQuantiler.
+	latency Quantiler
+
+	// routes tracks the per (route, method) breakdown exposed by
+	// DataDetailed. Populated by ObserveRoute/HandlerFor, ticked on its own
+	// fixed 1-second ticker (see New) independent of resetInterval: its
+	// ring buckets are defined as one real second each, and the Rate1m/
+	// Rate5m/Rate15m windows they back would silently misreport if the
+	// ticker driving them ran at any other cadence.
+	routes *routeTracker
+
+	// sinks fan every sample observed in EndWithOptions out to external
+	// systems (StatsD, InfluxDB, OpenTelemetry, ...) in addition to the
+	// in-memory aggregation above. Configured via WithSink.
+	sinks             []*sinkDispatcher
+	sinkConfigs       []Sink
+	sinkBufferSize    int
+	sinkFlushInterval time.Duration
+}
+
+// Option configures optional behavior passed to New.
+type Option func(*Stats)
+
+// WithResetInterval overrides the default 1-second interval at which
+// ResponseCounts/TotalResponseCounts are refreshed. It does not affect the
+// per-route rate windows (see DataDetailed), which always tick once per
+// real second regardless of resetInterval.
+func WithResetInterval(d time.Duration) Option {
+	return func(s *Stats) {
+		s.resetInterval = d
+	}
+}
+
+// WithoutResetter disables the background resetter goroutine entirely,
+// including the per-route rate window ticker. Useful in tests, or when a
+// caller wants to drive ResetResponseCounts on its own schedule. With it
+// set, nothing advances the per-route rate windows, so Rate1m/Rate5m/
+// Rate15m in DataDetailed are not windowed.
+func WithoutResetter() Option {
+	return func(s *Stats) {
+		s.noResetter = true
+	}
+}
+
+// WithSink adds a Sink that every request observed by EndWithOptions is
+// fanned out to, in addition to the in-memory aggregation Stats already
+// performs. Each sink gets its own bounded channel and background
+// goroutine (see sinkDispatcher), so a slow or unreachable sink can't
+// add latency to the request path. May be passed more than once.
+func WithSink(sink Sink) Option {
+	return func(s *Stats) {
+		s.sinkConfigs = append(s.sinkConfigs, sink)
+	}
+}
+
+// WithSinkBufferSize overrides the default per-sink channel size (1024).
+// Once full, new samples are dropped rather than blocking the request
+// path; see sinkDispatcher.Dropped.
+func WithSinkBufferSize(n int) Option {
+	return func(s *Stats) {
+		s.sinkBufferSize = n
+	}
+}
+
+// WithSinkFlushInterval overrides the default 10s interval at which
+// sinks are flushed.
+func WithSinkFlushInterval(d time.Duration) Option {
+	return func(s *Stats) {
+		s.sinkFlushInterval = d
+	}
 }
 
 // New constructs a new Stats structure
-func New() *Stats {
+func New(opts ...Option) *Stats {
 	stats := &Stats{
 		closed:              make(chan struct{}, 1),
 		Uptime:              time.Now(),
 		Pid:                 os.Getpid(),
 		ResponseCounts:      map[string]int{},
 		TotalResponseCounts: map[string]int{},
-		TotalResponseTime:   time.Time{},
+		intervalCounts:      newAtomicCounters(),
+		totalCounts:         newAtomicCounters(),
+		methodStatusCounts:  newMethodStatusCounters(),
+		resetInterval:       time.Second,
+		latency:             NewDigest(100),
+		routes:              newRouteTracker(),
 	}
 
-	go func() {
-		for {
-			select {
-			case <-stats.closed:
-				return
-			default:
-				stats.ResetResponseCounts()
+	for _, opt := range opts {
+		opt(stats)
+	}
 
-				time.Sleep(time.Second * 1)
+	for _, sink := range stats.sinkConfigs {
+		stats.sinks = append(stats.sinks, newSinkDispatcher(sink, stats.sinkBufferSize, stats.sinkFlushInterval))
+	}
+
+	if !stats.noResetter {
+		go func() {
+			ticker := time.NewTicker(stats.resetInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-stats.closed:
+					return
+				case <-ticker.C:
+					stats.ResetResponseCounts()
+				}
+			}
+		}()
+
+		// routes.tick's ring buckets are defined as one real second each
+		// (see routeWindow.rate), so it gets its own fixed 1-second
+		// ticker rather than sharing resetInterval, which WithResetInterval
+		// lets callers set to anything.
+		go func() {
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-stats.closed:
+					return
+				case <-ticker.C:
+					stats.routes.tick()
+				}
 			}
-		}
-	}()
+		}()
+	}
 
 	return stats
 }
 
 func (mw *Stats) Close() {
 	close(mw.closed)
+
+	for _, d := range mw.sinks {
+		d.close()
+	}
 }
 
-// ResetResponseCounts reset the response counts
-func (mw *Stats) ResetResponseCounts() {
+// dispatchSample fans sample out to every configured sink.
+func (mw *Stats) dispatchSample(sample Sample) {
+	for _, d := range mw.sinks {
+		d.enqueue(sample)
+	}
+}
+
+// SetLatencyQuantiler replaces the Quantiler used to estimate response
+// time percentiles, e.g. with an HDR-histogram-backed fixed bucket
+// implementation instead of the default t-digest. It must be called
+// before Stats starts serving traffic; it isn't safe to swap mid-flight.
+func (mw *Stats) SetLatencyQuantiler(q Quantiler) {
 	mw.mu.Lock()
 	defer mw.mu.Unlock()
-	mw.ResponseCounts = map[string]int{}
+	mw.latency = q
+}
+
+// ResetResponseCounts refreshes ResponseCounts/TotalResponseCounts from
+// the lock-free counters and clears the interval counter for the next
+// window. Safe to call concurrently with EndWithOptions; the ticker
+// started by New calls it once per resetInterval by default.
+func (mw *Stats) ResetResponseCounts() {
+	intervalSnapshot := mw.intervalCounts.snapshot()
+	mw.intervalCounts.reset()
+
+	totalSnapshot := mw.totalCounts.snapshot()
+	totalResponseSize := mw.totalResponseSize.Load()
+
+	mw.mu.Lock()
+	mw.ResponseCounts = intervalSnapshot
+	mw.TotalResponseCounts = totalSnapshot
+	mw.TotalResponseSize = totalResponseSize
+	mw.mu.Unlock()
 }
 
 // Handler is a MiddlewareFunc makes Stats implement the Middleware interface.
@@ -88,24 +255,102 @@ func (mw *Stats) Begin(w http.ResponseWriter) (time.Time, ResponseWriter) {
 
 // EndWithStatus closes the recorder with a specific status
 func (mw *Stats) EndWithStatus(start time.Time, status int) {
-	end := time.Now()
+	mw.EndWithOptions(start, Options{StatusCode: &status})
+}
 
-	responseTime := end.Sub(start)
+// End closes the recorder with the recorder status
+func (mw *Stats) End(start time.Time, recorder ResponseWriter) {
+	mw.EndWithStatus(start, recorder.Status())
+}
 
-	mw.mu.Lock()
+// Options configures EndWithOptions. It lets callers that already
+// maintain their own response recorder (reverse-proxy middlewares such as
+// Traefik being the common case) report the status code and bytes
+// written directly, instead of being forced through the double-wrapping
+// NewRecorderResponseWriter otherwise requires.
+type Options struct {
+	// StatusCode is the status code to record. If nil, Recorder.Status()
+	// is used instead, so at least one of the two must be set.
+	StatusCode *int
+
+	// Size is the number of bytes written to the response body. If zero
+	// and Recorder is set, Recorder.Size() is used instead.
+	Size int
+
+	// Method is the HTTP method to record in StatusCodeCountByMethod and
+	// to tag Sink samples with. If empty, the method breakdown is left
+	// untouched for this call.
+	Method string
+
+	// Route tags Sink samples with a route (typically a registered
+	// pattern such as "/users/{id}"). Left empty if the caller doesn't
+	// have one.
+	Route string
+
+	// Recorder, when set, supplies StatusCode and/or Size whenever they
+	// are left unset, so a caller that already has a ResponseWriter
+	// doesn't have to read its fields out by hand.
+	Recorder ResponseWriter
+
+	// Duration, when set, is recorded as the response time instead of
+	// time.Since(start). Callers that already measured the request's
+	// duration for their own purposes (e.g. a middleware also recording
+	// it against an external metrics system) should set this so both
+	// ends of the request agree on exactly the same duration.
+	Duration *time.Duration
+}
 
-	defer mw.mu.Unlock()
+// EndWithOptions closes the recorder described by opts, recording its
+// status code, response time and response size. Unlike End/EndWithStatus
+// it doesn't require a stats.ResponseWriter: a caller can pass whatever
+// status/size it already tracked, or hand over its own Recorder.
+func (mw *Stats) EndWithOptions(start time.Time, opts Options) {
+	responseTime := time.Since(start)
+	if opts.Duration != nil {
+		responseTime = *opts.Duration
+	}
 
-	statusCode := fmt.Sprintf("%d", status)
+	status := 0
+	if opts.StatusCode != nil {
+		status = *opts.StatusCode
+	} else if opts.Recorder != nil {
+		status = opts.Recorder.Status()
+	}
+
+	size := opts.Size
+	if size == 0 && opts.Recorder != nil {
+		size = opts.Recorder.Size()
+	}
 
-	mw.ResponseCounts[statusCode]++
-	mw.TotalResponseCounts[statusCode]++
-	mw.TotalResponseTime = mw.TotalResponseTime.Add(responseTime)
+	// Hot path: no locking, just atomic adds.
+	mw.intervalCounts.add(status, 1)
+	mw.totalCounts.add(status, 1)
+	mw.totalResponseTimeNanos.Add(responseTime.Nanoseconds())
+	mw.totalResponseSize.Add(int64(size))
+	mw.latency.Observe(responseTime.Seconds())
+
+	mw.dispatchSample(Sample{
+		Method:   opts.Method,
+		Route:    opts.Route,
+		Code:     status,
+		Duration: responseTime,
+		Size:     size,
+		Time:     start.Add(responseTime),
+	})
+
+	if opts.Method != "" {
+		mw.methodStatusCounts.add(opts.Method, status, 1)
+	}
 }
 
-// End closes the recorder with the recorder status
-func (mw *Stats) End(start time.Time, recorder ResponseWriter) {
-	mw.EndWithStatus(start, recorder.Status())
+// ObserveRoute records a single request's outcome against the per
+// (route, method) breakdown exposed by DataDetailed, without touching any
+// of the other bookkeeping EndWithOptions performs. It's exported so
+// external middlewares that already maintain their own recorder and
+// duration (e.g. a Prometheus-backed one) can feed that breakdown without
+// measuring the request a second time; HandlerFor uses it internally.
+func (mw *Stats) ObserveRoute(method, route string, status int, responseTime time.Duration) {
+	mw.routes.observe(method, route, status, responseTime)
 }
 
 // Data serializable structure
@@ -123,33 +368,52 @@ type Data struct {
 	TotalResponseTimeSec   float64        `json:"total_response_time_sec"`
 	AverageResponseTime    string         `json:"average_response_time"`
 	AverageResponseTimeSec float64        `json:"average_response_time_sec"`
+	TotalResponseSize      int64          `json:"total_response_size"`
+
+	// StatusCodeCountByMethod is a lifetime count of responses by method,
+	// then status code, e.g. {"GET": {"200": 42, "404": 1}}.
+	StatusCodeCountByMethod map[string]map[string]int `json:"status_code_count_by_method"`
+
+	MinResponseTimeSec float64 `json:"min_response_time_sec"`
+	MaxResponseTimeSec float64 `json:"max_response_time_sec"`
+	P50ResponseTimeSec float64 `json:"p50_response_time_sec"`
+	P90ResponseTimeSec float64 `json:"p90_response_time_sec"`
+	P95ResponseTimeSec float64 `json:"p95_response_time_sec"`
+	P99ResponseTimeSec float64 `json:"p99_response_time_sec"`
 }
 
-// Data returns the data serializable structure
+// Data returns the data serializable structure. Count, TotalCount and the
+// response-time/size totals are read straight from the lock-free counters,
+// so they're accurate on every call regardless of whether the background
+// resetter (see WithoutResetter) is running; only the reset-interval
+// windowing of StatusCodeCount depends on it.
 func (mw *Stats) Data() *Data {
 
 	mw.mu.RLock()
-
-	responseCounts := make(map[string]int, len(mw.ResponseCounts))
-	totalResponseCounts := make(map[string]int, len(mw.TotalResponseCounts))
+	pid := mw.Pid
+	uptimeStart := mw.Uptime
+	latency := mw.latency
+	mw.mu.RUnlock()
 
 	now := time.Now()
+	uptime := now.Sub(uptimeStart)
 
-	uptime := now.Sub(mw.Uptime)
+	responseCounts := mw.intervalCounts.snapshot()
+	totalResponseCounts := mw.totalCounts.snapshot()
 
 	count := 0
-	for code, current := range mw.ResponseCounts {
-		responseCounts[code] = current
+	for _, current := range responseCounts {
 		count += current
 	}
 
 	totalCount := 0
-	for code, count := range mw.TotalResponseCounts {
-		totalResponseCounts[code] = count
-		totalCount += count
+	for _, current := range totalResponseCounts {
+		totalCount += current
 	}
 
-	totalResponseTime := mw.TotalResponseTime.Sub(time.Time{})
+	totalResponseSize := mw.totalResponseSize.Load()
+
+	totalResponseTime := time.Duration(mw.totalResponseTimeNanos.Load())
 
 	averageResponseTime := time.Duration(0)
 	if totalCount > 0 {
@@ -157,22 +421,28 @@ func (mw *Stats) Data() *Data {
 		averageResponseTime = time.Duration(avgNs)
 	}
 
-	mw.mu.RUnlock()
-
 	r := &Data{
-		Pid:                    mw.Pid,
-		UpTime:                 uptime.String(),
-		UpTimeSec:              uptime.Seconds(),
-		Time:                   now.String(),
-		TimeUnix:               now.Unix(),
-		StatusCodeCount:        responseCounts,
-		TotalStatusCodeCount:   totalResponseCounts,
-		Count:                  count,
-		TotalCount:             totalCount,
-		TotalResponseTime:      totalResponseTime.String(),
-		TotalResponseTimeSec:   totalResponseTime.Seconds(),
-		AverageResponseTime:    averageResponseTime.String(),
-		AverageResponseTimeSec: averageResponseTime.Seconds(),
+		Pid:                     pid,
+		UpTime:                  uptime.String(),
+		UpTimeSec:               uptime.Seconds(),
+		Time:                    now.String(),
+		TimeUnix:                now.Unix(),
+		StatusCodeCount:         responseCounts,
+		TotalStatusCodeCount:    totalResponseCounts,
+		Count:                   count,
+		TotalCount:              totalCount,
+		TotalResponseTime:       totalResponseTime.String(),
+		TotalResponseTimeSec:    totalResponseTime.Seconds(),
+		AverageResponseTime:     averageResponseTime.String(),
+		AverageResponseTimeSec:  averageResponseTime.Seconds(),
+		TotalResponseSize:       totalResponseSize,
+		StatusCodeCountByMethod: mw.methodStatusCounts.snapshot(),
+		MinResponseTimeSec:      latency.Min(),
+		MaxResponseTimeSec:      latency.Max(),
+		P50ResponseTimeSec:      latency.Quantile(0.50),
+		P90ResponseTimeSec:      latency.Quantile(0.90),
+		P95ResponseTimeSec:      latency.Quantile(0.95),
+		P99ResponseTimeSec:      latency.Quantile(0.99),
 	}
 
 	return r
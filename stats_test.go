@@ -0,0 +1,87 @@
+package stats
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestHandlerConcurrent hammers Handler from many goroutines while Data()
+// and the reset ticker run concurrently, exercising the lock-free counters
+// and the mu-guarded snapshot fields together under `go test -race`.
+func TestHandlerConcurrent(t *testing.T) {
+	mw := New(WithResetInterval(time.Millisecond))
+	defer mw.Close()
+
+	handler := mw.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	const goroutines = 50
+	const requestsPerGoroutine = 100
+
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < requestsPerGoroutine; j++ {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+			}
+		}()
+	}
+
+	stop := make(chan struct{})
+	var readerWG sync.WaitGroup
+	readerWG.Add(1)
+	go func() {
+		defer readerWG.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				mw.Data()
+			}
+		}
+	}()
+
+	wg.Wait()
+	close(stop)
+	readerWG.Wait()
+
+	data := mw.Data()
+	if want := goroutines * requestsPerGoroutine; data.TotalCount != want {
+		t.Fatalf("TotalCount = %d, want %d", data.TotalCount, want)
+	}
+}
+
+// TestDataWithoutResetter verifies Data() stays accurate even when the
+// background resetter is disabled, per WithoutResetter's documented use
+// from a caller's own schedule.
+func TestDataWithoutResetter(t *testing.T) {
+	mw := New(WithoutResetter())
+	defer mw.Close()
+
+	handler := mw.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i := 0; i < 10; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+	}
+
+	data := mw.Data()
+	if data.TotalCount != 10 {
+		t.Fatalf("TotalCount = %d, want 10", data.TotalCount)
+	}
+	if data.Count != 10 {
+		t.Fatalf("Count = %d, want 10", data.Count)
+	}
+}
@@ -0,0 +1,68 @@
+// Package statsotel provides an OpenTelemetry-backed stats.Sink, kept out
+// of the dependency-free core stats package so callers that don't need
+// OpenTelemetry don't pull in its SDK.
+package statsotel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/AzureCloudMonk/stats"
+)
+
+// OTelSink records samples as OpenTelemetry metrics: an
+// http.server.duration histogram (seconds) and an
+// http.server.response.size histogram (bytes), both attributed with
+// method/route/status code. It only records instruments; the caller
+// owns the MeterProvider and whatever exporter (typically OTLP) it's
+// wired up to.
+type OTelSink struct {
+	duration metric.Float64Histogram
+	size     metric.Int64Histogram
+}
+
+// NewOTelSink creates the instruments on meter, e.g. the Meter from
+// otel.GetMeterProvider().Meter("github.com/AzureCloudMonk/stats"), and
+// returns it as a stats.Sink ready to pass to stats.WithSink.
+func NewOTelSink(meter metric.Meter) (stats.Sink, error) {
+	duration, err := meter.Float64Histogram(
+		"http.server.duration",
+		metric.WithDescription("HTTP request duration"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := meter.Int64Histogram(
+		"http.server.response.size",
+		metric.WithDescription("HTTP response size"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OTelSink{duration: duration, size: size}, nil
+}
+
+func (s *OTelSink) Record(sample stats.Sample) {
+	attrs := metric.WithAttributes(
+		attribute.String("http.method", sample.Method),
+		attribute.String("http.route", sample.Route),
+		attribute.Int("http.status_code", sample.Code),
+	)
+
+	ctx := context.Background()
+	s.duration.Record(ctx, sample.Duration.Seconds(), attrs)
+	s.size.Record(ctx, int64(sample.Size), attrs)
+}
+
+// Flush is a no-op: the OTLP exporter configured on the MeterProvider
+// (typically via a PeriodicReader) owns its own export cadence, so
+// there's nothing left for the sink itself to push here.
+func (s *OTelSink) Flush(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,32 @@
+package statsotel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/metric/noop"
+
+	"github.com/AzureCloudMonk/stats"
+)
+
+func TestNewOTelSinkRecordsWithoutError(t *testing.T) {
+	meter := noop.NewMeterProvider().Meter("stats-test")
+
+	sink, err := NewOTelSink(meter)
+	if err != nil {
+		t.Fatalf("NewOTelSink: %v", err)
+	}
+
+	sink.Record(stats.Sample{
+		Method:   "GET",
+		Route:    "/users/{id}",
+		Code:     200,
+		Duration: 10 * time.Millisecond,
+		Size:     256,
+	})
+
+	if err := sink.Flush(context.Background()); err != nil {
+		t.Errorf("Flush: %v, want nil (no-op sink)", err)
+	}
+}
@@ -0,0 +1,157 @@
+// Package statsprom provides a Prometheus-backed middleware on top of
+// stats.Stats, kept out of the dependency-free core stats package so
+// callers that don't need Prometheus don't pull in its client library.
+package statsprom
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/AzureCloudMonk/stats"
+)
+
+// DefaultDurationBuckets are the histogram buckets (in seconds) used for
+// http_request_duration_seconds unless overridden in New.
+var DefaultDurationBuckets = prometheus.DefBuckets
+
+// DefaultSizeBuckets are the histogram buckets (in bytes) used for
+// http_response_size_bytes unless overridden in New.
+var DefaultSizeBuckets = prometheus.ExponentialBuckets(100, 10, 7)
+
+// Middleware wraps a *stats.Stats with Prometheus collectors (requests
+// total, duration, response size, in-flight gauge, process uptime),
+// exposed via MetricsHandler/Register in addition to Stats.Data().
+type Middleware struct {
+	Stats *stats.Stats
+
+	registry         *prometheus.Registry
+	requestsTotal    *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+	responseSize     *prometheus.HistogramVec
+	requestsInFlight prometheus.Gauge
+	uptime           prometheus.GaugeFunc
+}
+
+// New builds the Prometheus collectors and registers them, along with an
+// uptime gauge, into a private registry so MetricsHandler works out of the
+// box without the caller wiring anything up. A nil durationBuckets or
+// sizeBuckets falls back to DefaultDurationBuckets/DefaultSizeBuckets.
+func New(s *stats.Stats, durationBuckets, sizeBuckets []float64) *Middleware {
+	if durationBuckets == nil {
+		durationBuckets = DefaultDurationBuckets
+	}
+	if sizeBuckets == nil {
+		sizeBuckets = DefaultSizeBuckets
+	}
+
+	m := &Middleware{Stats: s}
+
+	m.registry = prometheus.NewRegistry()
+
+	m.requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Total number of HTTP requests processed, labeled by method, route and status code.",
+	}, []string{"method", "route", "code"})
+
+	m.requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency in seconds, labeled by method, route and status code.",
+		Buckets: durationBuckets,
+	}, []string{"method", "route", "code"})
+
+	m.responseSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_response_size_bytes",
+		Help:    "HTTP response size in bytes, labeled by method, route and status code.",
+		Buckets: sizeBuckets,
+	}, []string{"method", "route", "code"})
+
+	m.requestsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "Number of HTTP requests currently being served.",
+	})
+
+	m.uptime = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "process_uptime_seconds",
+		Help: "Time in seconds since the process (and this Stats instance) started.",
+	}, func() float64 {
+		return time.Since(s.Uptime).Seconds()
+	})
+
+	m.registry.MustRegister(
+		m.requestsTotal,
+		m.requestDuration,
+		m.responseSize,
+		m.requestsInFlight,
+		m.uptime,
+	)
+
+	return m
+}
+
+// Register adds m's Prometheus collectors to reg, so they can be exposed
+// alongside the rest of an application's metrics instead of (or in
+// addition to) the registry served by MetricsHandler.
+func (m *Middleware) Register(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{
+		m.requestsTotal,
+		m.requestDuration,
+		m.responseSize,
+		m.requestsInFlight,
+		m.uptime,
+	} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MetricsHandler returns an http.Handler that serves m's metrics in the
+// Prometheus exposition format, suitable for mounting at /metrics.
+func (m *Middleware) MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *Middleware) observe(method, route string, status int, duration time.Duration, size int) {
+	code := strconv.Itoa(status)
+
+	m.requestsTotal.WithLabelValues(method, route, code).Inc()
+	m.requestDuration.WithLabelValues(method, route, code).Observe(duration.Seconds())
+	m.responseSize.WithLabelValues(method, route, code).Observe(float64(size))
+}
+
+// HandlerRoute wraps h, labeling the request with pattern so method/route/
+// code can be recorded instead of reconstructing the route from
+// r.URL.Path (which doesn't know about path parameters), and tracks it in
+// http_requests_in_flight for the duration of the call. The request's
+// duration is measured once and passed to both the Prometheus collectors
+// and m.Stats's per-route breakdown, so they can't diverge.
+func (m *Middleware) HandlerRoute(pattern string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.requestsInFlight.Inc()
+		defer m.requestsInFlight.Dec()
+
+		start, recorder := m.Stats.Begin(w)
+
+		h.ServeHTTP(recorder, r)
+
+		status := recorder.Status()
+		duration := time.Since(start)
+		size := recorder.Size()
+
+		m.observe(r.Method, pattern, status, duration, size)
+		m.Stats.ObserveRoute(r.Method, pattern, status, duration)
+		m.Stats.EndWithOptions(start, stats.Options{
+			StatusCode: &status,
+			Size:       size,
+			Method:     r.Method,
+			Route:      pattern,
+			Duration:   &duration,
+		})
+	})
+}
@@ -0,0 +1,45 @@
+package statsprom
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AzureCloudMonk/stats"
+)
+
+func TestHandlerRouteExposesMetrics(t *testing.T) {
+	s := stats.New(stats.WithoutResetter())
+	defer s.Close()
+
+	m := New(s, nil, nil)
+
+	handler := m.HandlerRoute("/users/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	rec := httptest.NewRecorder()
+	m.MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body, err := io.ReadAll(rec.Result().Body)
+	if err != nil {
+		t.Fatalf("reading metrics response: %v", err)
+	}
+	got := string(body)
+
+	want := `http_requests_total{code="200",method="GET",route="/users/{id}"} 2`
+	if !strings.Contains(got, want) {
+		t.Errorf("metrics output missing %q, got:\n%s", want, got)
+	}
+
+	if data := s.Data(); data.TotalCount != 2 {
+		t.Errorf("Stats.Data().TotalCount = %d, want 2 (HandlerRoute must feed the underlying Stats too)", data.TotalCount)
+	}
+}